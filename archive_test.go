@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestZip(t *testing.T, path string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanArchiveZipRootsEntriesUnderBaseName(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "comic.cbz")
+	writeTestZip(t, archivePath, "page1.png", "page2.jpg", "notes.txt")
+
+	var found []foundImage
+	seen := make(map[string]bool)
+	if err := scanArchive(archivePath, "", nil, &found, seen); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("scanArchive found %d images, want 2 (non-image entry should be skipped)", len(found))
+	}
+	for _, f := range found {
+		if filepath.Dir(f.RelPath) != "comic" {
+			t.Errorf("entry RelPath %q not rooted under archive base name \"comic\"", f.RelPath)
+		}
+	}
+}
+
+func TestScanArchiveZipDedupsRepeatedCall(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "comic.cbz")
+	writeTestZip(t, archivePath, "page1.png")
+
+	var found []foundImage
+	seen := make(map[string]bool)
+	if err := scanArchive(archivePath, "", nil, &found, seen); err != nil {
+		t.Fatal(err)
+	}
+	if err := scanArchive(archivePath, "", nil, &found, seen); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 1 {
+		t.Errorf("scanning the same archive twice produced %d entries, want 1 (deduped)", len(found))
+	}
+}
+
+func TestScanArchiveNsPrefixSeparatesSameNameArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "d1", "comic.cbz"), "page1.png")
+	writeTestZip(t, filepath.Join(dir, "d2", "comic.cbz"), "page1.png")
+
+	var found []foundImage
+	seen := make(map[string]bool)
+	if err := scanArchive(filepath.Join(dir, "d1", "comic.cbz"), "d1", nil, &found, seen); err != nil {
+		t.Fatal(err)
+	}
+	if err := scanArchive(filepath.Join(dir, "d2", "comic.cbz"), "d2", nil, &found, seen); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("scanArchive with distinct nsPrefixes produced %d entries, want 2", len(found))
+	}
+	if found[0].RelPath == found[1].RelPath {
+		t.Errorf("same-named archives under different nsPrefixes collided: both RelPath = %q", found[0].RelPath)
+	}
+}
+
+func TestScanImagesRoutesArchiveFoundViaGlob(t *testing.T) {
+	root := t.TempDir()
+	writeTestZip(t, filepath.Join(root, "comics", "c1.cbz"), "page1.png")
+
+	found, err := scanImages([]string{filepath.Join(root, "comics", "*.cbz")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("scanImages via glob over a .cbz = %d entries, want 1 (archive should be opened, not skipped)", len(found))
+	}
+}
+
+func TestScanImagesRoutesArchiveFoundViaDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeTestZip(t, filepath.Join(root, "comics", "c1.cbz"), "page1.png")
+
+	found, err := scanImages([]string{filepath.Join(root, "comics")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("scanImages over a directory containing a .cbz = %d entries, want 1 (archive should be opened, not skipped)", len(found))
+	}
+}
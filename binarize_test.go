@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSauvolaBinarizeFlatImageStaysWhite(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetGray(x, y, color.Gray{Y: 200})
+		}
+	}
+
+	out := sauvolaBinarize(img, 3, 0.5)
+
+	b := out.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c := color.GrayModel.Convert(out.At(x, y)).(color.Gray); c.Y != 255 {
+				t.Fatalf("pixel (%d,%d) = %d, want 255 on a uniform-brightness image", x, y, c.Y)
+			}
+		}
+	}
+}
+
+func TestSauvolaBinarizeFindsTextStrokeAgainstBackground(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 9, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			img.SetGray(x, y, color.Gray{Y: 230})
+		}
+	}
+	img.SetGray(4, 4, color.Gray{Y: 20})
+
+	out := sauvolaBinarize(img, 5, 0.2)
+
+	stroke := color.GrayModel.Convert(out.At(4, 4)).(color.Gray)
+	background := color.GrayModel.Convert(out.At(0, 0)).(color.Gray)
+
+	if stroke.Y != 0 {
+		t.Errorf("text stroke pixel = %d, want 0 (black)", stroke.Y)
+	}
+	if background.Y != 255 {
+		t.Errorf("uniform background pixel = %d, want 255 (white)", background.Y)
+	}
+}
+
+func TestSauvolaBinarizePreservesBounds(t *testing.T) {
+	img := image.NewGray(image.Rect(2, 3, 10, 9))
+	out := sauvolaBinarize(img, 5, 0.5)
+
+	if out.Bounds() != img.Bounds() {
+		t.Errorf("sauvolaBinarize bounds = %v, want %v", out.Bounds(), img.Bounds())
+	}
+}
@@ -1,374 +1,1382 @@
-package main
-
-import (
-	"bufio"
-	"bytes"
-	"flag"
-	"fmt"
-	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
-	"strings"
-	"sync"
-)
-
-type ImageJob struct {
-	SourcePath string
-	DestPath   string
-	OrigSize   int64
-}
-
-type ConversionResult struct {
-	SourcePath string
-	DestPath   string
-	OrigSize   int64
-	NewSize    int64
-	Success    bool
-	Error      error
-}
-
-type Stats struct {
-	TotalFiles     int
-	ProcessedFiles int
-	OriginalSize   int64
-	ConvertedSize  int64
-	FailedFiles    int
-}
-
-const (
-	outputDir = "compressed"
-)
-
-var (
-	quality     int
-	skip        bool
-	ffmpegMutex sync.Mutex
-)
-
-func main() {
-	fmt.Println(`
-		█████            █████
-          ░░███            ░░███
- ████████  ░███  ████████   ░███ █████     ██████   ██████  █████████████   ████████  ████████   ██████   █████   █████   ██████  ████████
-░░███░░███ ░███ ░░███░░███  ░███░░███     ███░░███ ███░░███░░███░░███░░███ ░░███░░███░░███░░███ ███░░███ ███░░   ███░░   ███░░███░░███░░███
- ░███ ░███ ░███  ░███ ░███  ░██████░     ░███ ░░░ ░███ ░███ ░███ ░███ ░███  ░███ ░███ ░███ ░░░ ░███████ ░░█████ ░░█████ ░███ ░███ ░███ ░░░
- ░███ ░███ ░███  ░███ ░███  ░███░░███    ░███  ███░███ ░███ ░███ ░███ ░███  ░███ ░███ ░███     ░███░░░   ░░░░███ ░░░░███░███ ░███ ░███
- ░███████  █████ ████ █████ ████ █████   ░░██████ ░░██████  █████░███ █████ ░███████  █████    ░░██████  ██████  ██████ ░░██████  █████
- ░███░░░  ░░░░░ ░░░░ ░░░░░ ░░░░ ░░░░░     ░░░░░░   ░░░░░░  ░░░░░ ░░░ ░░░░░  ░███░░░  ░░░░░      ░░░░░░  ░░░░░░  ░░░░░░   ░░░░░░  ░░░░░
- ░███                                                                       ░███
- █████                                                                      █████
-░░░░░                                                                      ░░░░░                                                           `)
-
-	flag.IntVar(&quality, "quality", 80, "Qualidade da compressão (1-100)")
-	flag.IntVar(&quality, "q", 80, "Qualidade da compressão (1-100)")
-	flag.BoolVar(&skip, "skip", false, "Executar sem preview")
-	flag.BoolVar(&skip, "s", false, "Executar sem preview")
-	flag.Parse()
-
-	if quality < 1 || quality > 100 {
-		logError("Qualidade deve estar entre 1 e 100")
-		os.Exit(1)
-	}
-
-	args := flag.Args()
-	if len(args) == 0 {
-		logError("Uso: go run compressor.go <pasta> [flags]")
-		os.Exit(1)
-	}
-
-	inputDir := args[0]
-	if inputDir == "." {
-		var err error
-		inputDir, err = os.Getwd()
-		if err != nil {
-			logError(fmt.Sprintf("Erro ao obter diretório atual: %v", err))
-			os.Exit(1)
-		}
-	}
-
-	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
-		logError(fmt.Sprintf("Diretório não encontrado: %s", inputDir))
-		os.Exit(1)
-	}
-
-	outputPath := filepath.Join(inputDir, outputDir)
-
-	images, err := scanImages(inputDir)
-	if err != nil {
-		logError(fmt.Sprintf("Erro ao escanear imagens: %v", err))
-		os.Exit(1)
-	}
-
-	if len(images) == 0 {
-		logInfo("Nenhuma imagem encontrada (PNG, JPEG, JPG, GIF)")
-		return
-	}
-
-	logInfo(fmt.Sprintf("Encontradas %d imagens para processar", len(images)))
-	logInfo(fmt.Sprintf("Qualidade: %d%%", quality))
-	fmt.Println()
-
-	if !skip {
-		showPreview(images, outputPath)
-		if !confirmExecution() {
-			logInfo("Operação cancelada pelo usuário")
-			return
-		}
-		fmt.Println()
-	}
-
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		logError(fmt.Sprintf("Erro ao criar pasta de saída: %v", err))
-		os.Exit(1)
-	}
-
-	jobs := make(chan ImageJob, len(images))
-	results := make(chan ConversionResult, len(images))
-
-	var wg sync.WaitGroup
-	workers := runtime.NumCPU()
-
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go worker(jobs, results, &wg)
-	}
-
-	for _, imgPath := range images {
-		info, err := os.Stat(imgPath)
-		if err != nil {
-			continue
-		}
-
-		filename := filepath.Base(imgPath)
-		nameWithoutExt := strings.TrimSuffix(filename, filepath.Ext(filename))
-		destPath := filepath.Join(outputPath, nameWithoutExt+".webp")
-
-		jobs <- ImageJob{
-			SourcePath: imgPath,
-			DestPath:   destPath,
-			OrigSize:   info.Size(),
-		}
-	}
-	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	stats := Stats{TotalFiles: len(images)}
-
-	for result := range results {
-		if result.Success {
-			stats.ProcessedFiles++
-			stats.OriginalSize += result.OrigSize
-			stats.ConvertedSize += result.NewSize
-
-			reduction := float64(result.OrigSize-result.NewSize) / float64(result.OrigSize) * 100
-
-			logSuccess(fmt.Sprintf("%s (%s) → %s (%s) [%.1f%% redução]",
-				filepath.Base(result.SourcePath),
-				formatSize(result.OrigSize),
-				filepath.Base(result.DestPath),
-				formatSize(result.NewSize),
-				reduction,
-			))
-		} else {
-			stats.FailedFiles++
-			logError(fmt.Sprintf("%s: %v", filepath.Base(result.SourcePath), result.Error))
-		}
-	}
-
-	fmt.Println()
-	printSummary(stats)
-}
-
-func worker(jobs <-chan ImageJob, results chan<- ConversionResult, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for job := range jobs {
-		result := ConversionResult{
-			SourcePath: job.SourcePath,
-			DestPath:   job.DestPath,
-			OrigSize:   job.OrigSize,
-		}
-
-		newSize, err := convertToWebP(job.SourcePath, job.DestPath, quality)
-		if err != nil {
-			result.Success = false
-			result.Error = err
-		} else {
-			result.Success = true
-			result.NewSize = newSize
-		}
-
-		results <- result
-	}
-}
-
-func checkFFmpeg() bool {
-	cmd := exec.Command("ffmpeg", "-version")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err := cmd.Run()
-
-	if err != nil {
-		cmd = exec.Command("where", "ffmpeg")
-		if cmd.Run() != nil {
-			return false
-		}
-	}
-
-	return true
-}
-
-func convertToWebP(sourcePath, destPath string, quality int) (int64, error) {
-	ffmpegMutex.Lock()
-	defer ffmpegMutex.Unlock()
-
-	cmd := exec.Command("ffmpeg",
-		"-i", sourcePath,
-		"-c:v", "libwebp",
-		"-quality", fmt.Sprintf("%d", quality),
-		"-y",
-		destPath,
-	)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return 0, fmt.Errorf("ffmpeg error: %v - %s", err, stderr.String())
-	}
-
-	info, err := os.Stat(destPath)
-	if err != nil {
-		return 0, err
-	}
-
-	return info.Size(), nil
-}
-
-func scanImages(dir string) ([]string, error) {
-	var images []string
-	validExts := map[string]bool{
-		".png":  true,
-		".jpg":  true,
-		".jpeg": true,
-		".gif":  true,
-	}
-
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		ext := strings.ToLower(filepath.Ext(entry.Name()))
-		if validExts[ext] {
-			images = append(images, filepath.Join(dir, entry.Name()))
-		}
-	}
-
-	return images, nil
-}
-
-func showPreview(images []string, outputPath string) {
-	fmt.Println(strings.Repeat("─", 80))
-	logInfo("PREVIEW - Arquivos que serão convertidos:")
-	fmt.Println()
-
-	var totalSize int64
-
-	for _, imgPath := range images {
-		info, err := os.Stat(imgPath)
-		if err != nil {
-			continue
-		}
-
-		filename := filepath.Base(imgPath)
-		nameWithoutExt := strings.TrimSuffix(filename, filepath.Ext(filename))
-		destFilename := nameWithoutExt + ".webp"
-
-		totalSize += info.Size()
-
-		fmt.Printf("  %s (%s) → %s\n",
-			filename,
-			formatSize(info.Size()),
-			destFilename,
-		)
-	}
-
-	fmt.Println()
-	logInfo(fmt.Sprintf("Pasta de destino: %s", outputPath))
-	logInfo(fmt.Sprintf("Tamanho total: %s", formatSize(totalSize)))
-	fmt.Println(strings.Repeat("─", 80))
-}
-
-func confirmExecution() bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("\nDeseja continuar? (S/n): ")
-
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return false
-	}
-
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "" || response == "s" || response == "sim"
-}
-
-func formatSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
-func logInfo(msg string) {
-	fmt.Printf("\033[36m[INFO]\033[0m %s\n", msg)
-}
-
-func logSuccess(msg string) {
-	fmt.Printf("\033[32m[✓]\033[0m %s\n", msg)
-}
-
-func logError(msg string) {
-	fmt.Printf("\033[31m[✗]\033[0m %s\n", msg)
-}
-
-func printSummary(stats Stats) {
-	fmt.Println(strings.Repeat("─", 80))
-	logInfo(fmt.Sprintf("Total de arquivos: %d", stats.TotalFiles))
-	logSuccess(fmt.Sprintf("Processados: %d", stats.ProcessedFiles))
-
-	if stats.FailedFiles > 0 {
-		logError(fmt.Sprintf("Falhas: %d", stats.FailedFiles))
-	}
-
-	if stats.ProcessedFiles > 0 {
-		reduction := float64(stats.OriginalSize-stats.ConvertedSize) / float64(stats.OriginalSize) * 100
-		fmt.Printf("\033[36m[STATS]\033[0m Tamanho original: %s → Convertido: %s (%.1f%% redução)\n",
-			formatSize(stats.OriginalSize),
-			formatSize(stats.ConvertedSize),
-			reduction,
-		)
-	}
-
-	fmt.Println(strings.Repeat("─", 80))
-}
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/chai2010/webp"
+	"github.com/cheggaaa/pb/v3"
+	"github.com/disintegration/imaging"
+	"github.com/mattn/go-isatty"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ImageJob describes one file to convert. For sources that live on disk,
+// SourcePath is set and Open is nil. For entries streamed out of an
+// archive (.zip/.cbz/.cbr), SourcePath is empty and Open is a factory that
+// returns a fresh reader onto the entry, since zip/archive entries can't be
+// addressed by a plain filesystem path.
+type ImageJob struct {
+	SourcePath  string
+	DisplayName string
+	Open        func() (io.ReadCloser, error)
+	DestPath    string
+	DestName    string
+	OrigSize    int64
+	RelPath     string
+	ModTime     int64
+}
+
+type ConversionResult struct {
+	SourcePath  string
+	DisplayName string
+	DestPath    string
+	DestName    string
+	OrigSize    int64
+	NewSize     int64
+	Success     bool
+	Error       error
+	Data        []byte
+	RelPath     string
+	ModTime     int64
+	Hash        string
+	OutHash     string
+}
+
+type Stats struct {
+	TotalFiles     int
+	ProcessedFiles int
+	OriginalSize   int64
+	ConvertedSize  int64
+	FailedFiles    int
+}
+
+const (
+	outputDir = "compressed"
+
+	// manifestFileName is the resumable-run manifest, written under the
+	// output directory alongside the converted files.
+	manifestFileName = ".pink-manifest.json"
+)
+
+var (
+	quality           int
+	skip              bool
+	noProgress        bool
+	encoderName       string
+	excludes          stringList
+	outputArchivePath string
+	force             bool
+	verify            bool
+
+	maxWidth       int
+	maxHeight      int
+	autoRotate     bool
+	stripMetadata  bool
+	binarize       bool
+	binarizeWindow int
+	binarizeK      float64
+
+	// cbrTool is resolved once at startup (if any input is a .cbr archive)
+	// so missing-tool failures surface immediately instead of mid-batch.
+	cbrTool string
+
+	// openArchives and extractedDirs track resources opened while scanning
+	// archive inputs, cleaned up by main once all jobs have been queued.
+	openArchives  []io.Closer
+	extractedDirs []string
+)
+
+// Encoder converts a decoded image into WebP bytes written to w. Unlike the
+// old ffmpeg-subprocess path, implementations are expected to be safe for
+// concurrent use so workers no longer need to serialize around them.
+type Encoder interface {
+	Encode(src image.Image, w io.Writer, quality int) error
+}
+
+// libwebpEncoder encodes in-process via libwebp bindings. This is the
+// default backend: no subprocess per file, and no shared lock across workers.
+type libwebpEncoder struct{}
+
+func (libwebpEncoder) Encode(src image.Image, w io.Writer, quality int) error {
+	return webp.Encode(w, src, &webp.Options{Quality: float32(quality)})
+}
+
+// ffmpegEncoder shells out to ffmpeg, piping a PNG re-encode of the decoded
+// image in over stdin and reading the WebP back from stdout. Kept as an
+// opt-in backend for environments where the libwebp bindings aren't viable.
+type ffmpegEncoder struct{}
+
+func (ffmpegEncoder) Encode(src image.Image, w io.Writer, quality int) error {
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, src); err != nil {
+		return fmt.Errorf("ffmpeg encoder: erro ao preparar imagem: %v", err)
+	}
+
+	cmd := exec.Command("ffmpeg",
+		"-f", "image2pipe",
+		"-i", "-",
+		"-c:v", "libwebp",
+		"-quality", fmt.Sprintf("%d", quality),
+		"-f", "webp",
+		"-",
+	)
+	cmd.Stdin = &pngBuf
+	cmd.Stdout = w
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg error: %v - %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// newEncoder resolves the --encoder flag value to a concrete Encoder,
+// failing early (e.g. ffmpeg missing from PATH) rather than per-file.
+func newEncoder(name string) (Encoder, error) {
+	switch name {
+	case "", "libwebp":
+		return libwebpEncoder{}, nil
+	case "ffmpeg":
+		if !checkFFmpeg() {
+			return nil, fmt.Errorf("ffmpeg não encontrado no PATH")
+		}
+		return ffmpegEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("encoder desconhecido: %s (use ffmpeg ou libwebp)", name)
+	}
+}
+
+const progressBarTemplate = `{{ string . "prefix" }} {{ bar . "[" "█" "█" " " "]" }} {{ percent . }}`
+
+// stringList implements flag.Value so --exclude can be passed multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// ManifestEntry records a source's size, mtime and content hash, plus the
+// hash of the WebP bytes produced for it, as of its last successful
+// conversion, keyed by RelPath in Manifest.Entries.
+type ManifestEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+	OutHash string `json:"outHash"`
+}
+
+// Manifest is persisted as manifestFileName under the output directory so
+// a batch interrupted partway through can be resumed without redoing work
+// already converted in a previous run.
+type Manifest struct {
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+func manifestPath(outputPath string) string {
+	return filepath.Join(outputPath, manifestFileName)
+}
+
+// loadManifest reads the manifest from outputPath, returning an empty one
+// (rather than an error) when it doesn't exist yet or fails to parse, so a
+// first run or a manually-cleared output folder just reprocesses everything.
+func loadManifest(outputPath string) *Manifest {
+	data, err := os.ReadFile(manifestPath(outputPath))
+	if err != nil {
+		return &Manifest{Entries: make(map[string]ManifestEntry)}
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Entries == nil {
+		return &Manifest{Entries: make(map[string]ManifestEntry)}
+	}
+	return &m
+}
+
+// save writes m to outputPath via a temp file + rename so a process killed
+// mid-write can never leave a half-written manifest behind.
+func (m *Manifest) save(outputPath string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dst := manifestPath(outputPath)
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// webpDestName returns the posix-style WebP output name for a source found
+// at relPath, used for DestName/manifest keys/archive entry names alike.
+func webpDestName(relPath string) string {
+	relWithoutExt := strings.TrimSuffix(relPath, filepath.Ext(relPath))
+	return filepath.ToSlash(relWithoutExt + ".webp")
+}
+
+// hashFoundImage computes the SHA-256 of f's raw source bytes. Used both to
+// check a --verify skip candidate against its recorded manifest hash and,
+// for freshly processed files, mirrored via the io.TeeReader in
+// convertToWebP so the source is only read once.
+func hashFoundImage(f foundImage) (string, error) {
+	var r io.ReadCloser
+	var err error
+	if f.Open != nil {
+		r, err = f.Open()
+	} else {
+		r, err = os.Open(f.SourcePath)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile computes the SHA-256 of the file at path, used by --verify to
+// detect a destination WebP that went missing or got corrupted after the
+// manifest recorded it.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// filterResumable drops entries from found that a previous run already
+// converted successfully, per manifest, unless --force was passed. The
+// default check only compares size and mtime: the hashes recorded alongside
+// them are trusted rather than recomputed, so resuming a large interrupted
+// batch stays cheap. --verify re-hashes both the source and the destination
+// WebP (see verifyEntry), catching content that changed without bumping
+// mtime and outputs that went missing or were corrupted after the fact.
+func filterResumable(found []foundImage, manifest *Manifest, outputPath string) []foundImage {
+	if force {
+		return found
+	}
+
+	kept := found[:0]
+	for _, f := range found {
+		entry, ok := manifest.Entries[f.RelPath]
+		if ok && entry.Size == f.OrigSize && entry.ModTime == f.ModTime && verifyEntry(f, entry, outputPath) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// verifyEntry reports whether a manifest match found by filterResumable
+// should still be honored. Without --verify it's a no-op; with --verify it
+// re-hashes the source (catching content that changed without bumping
+// mtime) and re-hashes the destination WebP against the hash recorded for
+// it (catching an output that went missing or was corrupted afterwards).
+func verifyEntry(f foundImage, entry ManifestEntry, outputPath string) bool {
+	if !verify {
+		return true
+	}
+
+	hash, err := hashFoundImage(f)
+	if err != nil || hash != entry.Hash {
+		return false
+	}
+
+	destPath := filepath.Join(outputPath, filepath.FromSlash(webpDestName(f.RelPath)))
+	outHash, err := hashFile(destPath)
+	return err == nil && outHash == entry.OutHash
+}
+
+func main() {
+	fmt.Println(`
+		█████            █████
+          ░░███            ░░███
+ ████████  ░███  ████████   ░███ █████     ██████   ██████  █████████████   ████████  ████████   ██████   █████   █████   ██████  ████████
+░░███░░███ ░███ ░░███░░███  ░███░░███     ███░░███ ███░░███░░███░░███░░███ ░░███░░███░░███░░███ ███░░███ ███░░   ███░░   ███░░███░░███░░███
+ ░███ ░███ ░███  ░███ ░███  ░██████░     ░███ ░░░ ░███ ░███ ░███ ░███ ░███  ░███ ░███ ░███ ░░░ ░███████ ░░█████ ░░█████ ░███ ░███ ░███ ░░░
+ ░███ ░███ ░███  ░███ ░███  ░███░░███    ░███  ███░███ ░███ ░███ ░███ ░███  ░███ ░███ ░███     ░███░░░   ░░░░███ ░░░░███░███ ░███ ░███
+ ░███████  █████ ████ █████ ████ █████   ░░██████ ░░██████  █████░███ █████ ░███████  █████    ░░██████  ██████  ██████ ░░██████  █████
+ ░███░░░  ░░░░░ ░░░░ ░░░░░ ░░░░ ░░░░░     ░░░░░░   ░░░░░░  ░░░░░ ░░░ ░░░░░  ░███░░░  ░░░░░      ░░░░░░  ░░░░░░  ░░░░░░   ░░░░░░  ░░░░░
+ ░███                                                                       ░███
+ █████                                                                      █████
+░░░░░                                                                      ░░░░░                                                           `)
+
+	flag.IntVar(&quality, "quality", 80, "Qualidade da compressão (1-100)")
+	flag.IntVar(&quality, "q", 80, "Qualidade da compressão (1-100)")
+	flag.BoolVar(&skip, "skip", false, "Executar sem preview")
+	flag.BoolVar(&skip, "s", false, "Executar sem preview")
+	flag.Var(&excludes, "exclude", "Padrão a excluir (pode ser usado múltiplas vezes)")
+	flag.BoolVar(&noProgress, "no-progress", false, "Desativa a UI de progresso (usa saída em texto)")
+	flag.StringVar(&encoderName, "encoder", "libwebp", "Motor de codificação WebP: libwebp ou ffmpeg")
+	flag.IntVar(&maxWidth, "max-width", 0, "Largura máxima (redimensiona mantendo proporção, 0 = sem limite)")
+	flag.IntVar(&maxHeight, "max-height", 0, "Altura máxima (redimensiona mantendo proporção, 0 = sem limite)")
+	flag.BoolVar(&autoRotate, "auto-rotate", false, "Respeita a orientação EXIF em entradas JPEG")
+	flag.BoolVar(&stripMetadata, "strip-metadata", false, "Remove metadados explicitamente (já descartados ao decodificar)")
+	flag.BoolVar(&binarize, "binarize", false, "Binariza a imagem (threshold local de Sauvola)")
+	flag.IntVar(&binarizeWindow, "binarize-window", 15, "Tamanho da janela (w) usada no threshold de Sauvola")
+	flag.Float64Var(&binarizeK, "binarize-k", 0.2, "Parâmetro k usado no threshold de Sauvola")
+	flag.StringVar(&outputArchivePath, "output-archive", "", "Grava um único .cbz com todos os WebP convertidos, em vez de arquivos soltos")
+	flag.BoolVar(&force, "force", false, "Ignora o manifest de execução anterior e reprocessa tudo")
+	flag.BoolVar(&verify, "verify", false, "Reconfere hash e existência dos arquivos já processados antes de pular")
+	flag.Parse()
+
+	if quality < 1 || quality > 100 {
+		logError("Qualidade deve estar entre 1 e 100")
+		os.Exit(1)
+	}
+
+	encoder, err := newEncoder(encoderName)
+	if err != nil {
+		logError(fmt.Sprintf("Erro ao selecionar encoder: %v", err))
+		os.Exit(1)
+	}
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		logError("Uso: go run compressor.go <pasta|padrão...> [flags]")
+		os.Exit(1)
+	}
+
+	for i, p := range patterns {
+		if p == "." {
+			wd, err := os.Getwd()
+			if err != nil {
+				logError(fmt.Sprintf("Erro ao obter diretório atual: %v", err))
+				os.Exit(1)
+			}
+			patterns[i] = wd
+		}
+		if strings.EqualFold(filepath.Ext(p), ".cbr") && cbrTool == "" {
+			tool, err := findArchiveTool()
+			if err != nil {
+				logError(fmt.Sprintf("Erro: %v", err))
+				os.Exit(1)
+			}
+			cbrTool = tool
+		}
+	}
+
+	outputPath := outputDir
+
+	found, err := scanImages(patterns, excludes)
+	defer closeArchiveResources()
+	if err != nil {
+		logError(fmt.Sprintf("Erro ao escanear imagens: %v", err))
+		os.Exit(1)
+	}
+
+	if len(found) == 0 {
+		logInfo("Nenhuma imagem encontrada (PNG, JPEG, JPG, GIF)")
+		return
+	}
+
+	var manifest *Manifest
+	if outputArchivePath == "" {
+		manifest = loadManifest(outputPath)
+		if !force {
+			before := len(found)
+			found = filterResumable(found, manifest, outputPath)
+			if skipped := before - len(found); skipped > 0 {
+				logInfo(fmt.Sprintf("%d imagens já processadas em uma execução anterior (ignoradas; use --force para reprocessar)", skipped))
+			}
+		}
+		if len(found) == 0 {
+			logInfo("Nada a fazer: todas as imagens já foram processadas")
+			return
+		}
+	}
+
+	logInfo(fmt.Sprintf("Encontradas %d imagens para processar", len(found)))
+	logInfo(fmt.Sprintf("Qualidade: %d%%", quality))
+	fmt.Println()
+
+	if !skip {
+		showPreview(found, outputPath)
+		if !confirmExecution() {
+			logInfo("Operação cancelada pelo usuário")
+			return
+		}
+		fmt.Println()
+	}
+
+	var archiveWriter *zip.Writer
+	if outputArchivePath != "" {
+		archiveFile, err := os.Create(outputArchivePath)
+		if err != nil {
+			logError(fmt.Sprintf("Erro ao criar arquivo de saída: %v", err))
+			os.Exit(1)
+		}
+		defer archiveFile.Close()
+		archiveWriter = zip.NewWriter(archiveFile)
+		defer archiveWriter.Close()
+	} else if err := os.MkdirAll(outputPath, 0755); err != nil {
+		logError(fmt.Sprintf("Erro ao criar pasta de saída: %v", err))
+		os.Exit(1)
+	}
+
+	jobs := make(chan ImageJob, len(found))
+	results := make(chan ConversionResult, len(found))
+
+	var wg sync.WaitGroup
+	workers := runtime.NumCPU()
+
+	useProgress := !noProgress && isatty.IsTerminal(os.Stdout.Fd())
+
+	var workerBars []*pb.ProgressBar
+	var totalBar *pb.ProgressBar
+	var pool *pb.Pool
+
+	if useProgress {
+		workerBars = make([]*pb.ProgressBar, workers)
+		bars := make([]*pb.ProgressBar, 0, workers+1)
+		for i := range workerBars {
+			workerBars[i] = pb.ProgressBarTemplate(progressBarTemplate).New(1)
+			workerBars[i].Set("prefix", "ocioso")
+			bars = append(bars, workerBars[i])
+		}
+		totalBar = pb.ProgressBarTemplate(progressBarTemplate).New(len(found))
+		totalBar.Set("prefix", "Total")
+		bars = append(bars, totalBar)
+
+		var err error
+		pool, err = pb.StartPool(bars...)
+		if err != nil {
+			logError(fmt.Sprintf("Erro ao iniciar UI de progresso: %v", err))
+			useProgress = false
+			pool = nil
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		var bar *pb.ProgressBar
+		if useProgress {
+			bar = workerBars[i]
+		}
+		go worker(jobs, results, &wg, bar, totalBar, encoder)
+	}
+
+	for _, f := range found {
+		destName := webpDestName(f.RelPath)
+
+		job := ImageJob{
+			SourcePath:  f.SourcePath,
+			DisplayName: f.DisplayName,
+			Open:        f.Open,
+			DestName:    destName,
+			OrigSize:    f.OrigSize,
+			RelPath:     f.RelPath,
+			ModTime:     f.ModTime,
+		}
+
+		if archiveWriter == nil {
+			job.DestPath = filepath.Join(outputPath, filepath.FromSlash(destName))
+			if err := os.MkdirAll(filepath.Dir(job.DestPath), 0755); err != nil {
+				continue
+			}
+		}
+
+		jobs <- job
+	}
+	close(jobs)
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	stats := Stats{TotalFiles: len(found)}
+
+	// While the pool owns the terminal it's redrawing the bars continuously;
+	// a bare logError/fmt.Printf here would race that redraw and corrupt the
+	// display. Buffer failure lines instead and flush them as plain red log
+	// lines once the pool has stopped.
+	var failedLines []string
+	logFailure := func(msg string) {
+		if useProgress {
+			failedLines = append(failedLines, msg)
+			return
+		}
+		logError(msg)
+	}
+
+	for result := range results {
+		name := resultDisplayName(result)
+
+		if result.Success {
+			stats.ProcessedFiles++
+			stats.OriginalSize += result.OrigSize
+			stats.ConvertedSize += result.NewSize
+
+			if archiveWriter != nil {
+				w, err := archiveWriter.CreateHeader(&zip.FileHeader{Name: result.DestName, Method: zip.Store})
+				if err == nil {
+					_, err = w.Write(result.Data)
+				}
+				if err != nil {
+					stats.ProcessedFiles--
+					stats.FailedFiles++
+					logFailure(fmt.Sprintf("%s: erro ao gravar no arquivo de saída: %v", name, err))
+					continue
+				}
+			}
+
+			if manifest != nil {
+				manifest.Entries[result.RelPath] = ManifestEntry{
+					Size:    result.OrigSize,
+					ModTime: result.ModTime,
+					Hash:    result.Hash,
+					OutHash: result.OutHash,
+				}
+				// Saved as each file lands, not just at the end, so killing
+				// a large batch partway through still leaves a manifest
+				// that lets the next run resume from where it stopped.
+				if err := manifest.save(outputPath); err != nil {
+					logFailure(fmt.Sprintf("Erro ao salvar manifest: %v", err))
+				}
+			}
+
+			if !useProgress {
+				reduction := float64(result.OrigSize-result.NewSize) / float64(result.OrigSize) * 100
+
+				logSuccess(fmt.Sprintf("%s (%s) → %s (%s) [%.1f%% redução]",
+					name,
+					formatSize(result.OrigSize),
+					filepath.Base(result.DestName),
+					formatSize(result.NewSize),
+					reduction,
+				))
+			}
+		} else {
+			stats.FailedFiles++
+			logFailure(fmt.Sprintf("%s: %v", name, result.Error))
+		}
+	}
+
+	if pool != nil {
+		pool.Stop()
+	}
+
+	for _, msg := range failedLines {
+		logError(msg)
+	}
+
+	fmt.Println()
+	printSummary(stats)
+}
+
+func resultDisplayName(r ConversionResult) string {
+	if r.SourcePath != "" {
+		return filepath.Base(r.SourcePath)
+	}
+	return r.DisplayName
+}
+
+func worker(jobs <-chan ImageJob, results chan<- ConversionResult, wg *sync.WaitGroup, bar *pb.ProgressBar, totalBar *pb.ProgressBar, encoder Encoder) {
+	defer wg.Done()
+
+	for job := range jobs {
+		name := job.DisplayName
+		if job.SourcePath != "" {
+			name = filepath.Base(job.SourcePath)
+		}
+		if bar != nil {
+			bar.Set("prefix", name)
+			bar.SetCurrent(0)
+		}
+
+		result := ConversionResult{
+			SourcePath:  job.SourcePath,
+			DisplayName: job.DisplayName,
+			DestPath:    job.DestPath,
+			DestName:    job.DestName,
+			OrigSize:    job.OrigSize,
+			RelPath:     job.RelPath,
+			ModTime:     job.ModTime,
+		}
+
+		data, hash, err := convertToWebP(job, quality, encoder)
+		if err != nil {
+			result.Success = false
+			result.Error = err
+		} else {
+			result.Hash = hash
+			outHash := sha256.Sum256(data)
+			result.OutHash = hex.EncodeToString(outHash[:])
+			if job.DestPath != "" {
+				if writeErr := os.WriteFile(job.DestPath, data, 0644); writeErr != nil {
+					result.Success = false
+					result.Error = writeErr
+				} else {
+					result.Success = true
+					result.NewSize = int64(len(data))
+				}
+			} else {
+				result.Success = true
+				result.NewSize = int64(len(data))
+				result.Data = data
+			}
+		}
+
+		if bar != nil {
+			bar.SetCurrent(1)
+		}
+		if totalBar != nil {
+			totalBar.Increment()
+		}
+
+		results <- result
+	}
+
+	if bar != nil {
+		bar.Set("prefix", "concluído")
+	}
+}
+
+func checkFFmpeg() bool {
+	cmd := exec.Command("ffmpeg", "-version")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	if err != nil {
+		cmd = exec.Command("where", "ffmpeg")
+		if cmd.Run() != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// openSource opens a reader onto job's image data, whether it lives on disk
+// (SourcePath) or inside an archive (Open).
+func openSource(job ImageJob) (io.ReadCloser, error) {
+	if job.Open != nil {
+		return job.Open()
+	}
+	return os.Open(job.SourcePath)
+}
+
+// jobExt returns the lowercased file extension of job's source, used to
+// pick a decoder-independent hint like "is this a JPEG".
+func jobExt(job ImageJob) string {
+	name := job.SourcePath
+	if name == "" {
+		name = job.DisplayName
+	}
+	return strings.ToLower(filepath.Ext(name))
+}
+
+// convertToWebP decodes job's source image, runs it through the configured
+// preprocessing pipeline and returns the encoded WebP bytes together with
+// the SHA-256 hash of the raw source, streamed via an io.TeeReader during
+// decode so the source is only read once. The caller is responsible for
+// writing those bytes to disk or into an output archive.
+func convertToWebP(job ImageJob, quality int, encoder Encoder) ([]byte, string, error) {
+	srcFile, err := openSource(job)
+	if err != nil {
+		return nil, "", err
+	}
+	defer srcFile.Close()
+
+	h := sha256.New()
+	tee := io.TeeReader(srcFile, h)
+
+	src, _, err := image.Decode(tee)
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao decodificar imagem: %v", err)
+	}
+	// image.Decode doesn't always read to EOF (e.g. trailing chunks after
+	// the last scan it needs), so drain the rest to hash the whole file.
+	io.Copy(io.Discard, tee)
+
+	for _, stage := range buildPipeline(job) {
+		src = stage(src)
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(src, &buf, quality); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// pipelineStage is a single preprocessing step applied to the decoded image
+// before it reaches the encoder, e.g. resize, auto-rotate or binarize.
+type pipelineStage func(image.Image) image.Image
+
+// buildPipeline assembles the preprocessing chain selected via flags for a
+// given job. job is only consulted to read EXIF orientation for
+// --auto-rotate; every other stage operates purely on the decoded image.
+func buildPipeline(job ImageJob) []pipelineStage {
+	var stages []pipelineStage
+
+	if autoRotate {
+		orientation := readEXIFOrientation(job)
+		stages = append(stages, func(img image.Image) image.Image {
+			return applyOrientation(img, orientation)
+		})
+	}
+
+	if maxWidth > 0 || maxHeight > 0 {
+		stages = append(stages, resizeStage)
+	}
+
+	if binarize {
+		stages = append(stages, func(img image.Image) image.Image {
+			return sauvolaBinarize(img, binarizeWindow, binarizeK)
+		})
+	}
+
+	if stripMetadata {
+		// Decoding into image.Image already discards EXIF/XMP/ICC chunks,
+		// so this stage is a no-op kept for an explicit, self-documenting
+		// pipeline when --strip-metadata is passed.
+		stages = append(stages, func(img image.Image) image.Image { return img })
+	}
+
+	return stages
+}
+
+func resizeStage(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	limitW, limitH := maxWidth, maxHeight
+	if limitW <= 0 {
+		limitW = w
+	}
+	if limitH <= 0 {
+		limitH = h
+	}
+	if w <= limitW && h <= limitH {
+		return img
+	}
+
+	ratio := math.Min(float64(limitW)/float64(w), float64(limitH)/float64(h))
+	newW := int(math.Round(float64(w) * ratio))
+	newH := int(math.Round(float64(h) * ratio))
+
+	return imaging.Resize(img, newW, newH, imaging.Lanczos)
+}
+
+// readEXIFOrientation returns the EXIF orientation tag (1-8) for a JPEG
+// file, or 1 (no transform) if the source isn't JPEG or carries no tag.
+func readEXIFOrientation(job ImageJob) int {
+	ext := jobExt(job)
+	if ext != ".jpg" && ext != ".jpeg" {
+		return 1
+	}
+
+	f, err := openSource(job)
+	if err != nil {
+		return 1
+	}
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return 1
+	}
+
+	tag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+
+	orientation, err := tag.Int(0)
+	if err != nil {
+		return 1
+	}
+
+	return orientation
+}
+
+// applyOrientation rotates/flips img so it displays upright according to
+// the EXIF orientation tag (values 1-8, see the EXIF/TIFF spec).
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// sauvolaBinarize converts img to black/white using Sauvola local
+// thresholding: T = m*(1 + k*(s/R - 1)), where m and s are the local mean
+// and standard deviation in a window×window neighbourhood and R=128. Local
+// sums are computed via an integral image so each pixel's threshold is O(1).
+func sauvolaBinarize(img image.Image, window int, k float64) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, img, b.Min, draw.Src)
+
+	stride := w + 1
+	sum := make([]float64, stride*(h+1))
+	sumSq := make([]float64, stride*(h+1))
+	idx := func(x, y int) int { return y*stride + x }
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := float64(gray.GrayAt(b.Min.X+x, b.Min.Y+y).Y)
+			sum[idx(x+1, y+1)] = v + sum[idx(x, y+1)] + sum[idx(x+1, y)] - sum[idx(x, y)]
+			sumSq[idx(x+1, y+1)] = v*v + sumSq[idx(x, y+1)] + sumSq[idx(x+1, y)] - sumSq[idx(x, y)]
+		}
+	}
+
+	const R = 128.0
+	half := window / 2
+	out := image.NewGray(b)
+
+	for y := 0; y < h; y++ {
+		y0, y1 := maxInt(0, y-half), minInt(h-1, y+half)
+		for x := 0; x < w; x++ {
+			x0, x1 := maxInt(0, x-half), minInt(w-1, x+half)
+			area := float64((x1 - x0 + 1) * (y1 - y0 + 1))
+
+			s := sum[idx(x1+1, y1+1)] - sum[idx(x0, y1+1)] - sum[idx(x1+1, y0)] + sum[idx(x0, y0)]
+			sq := sumSq[idx(x1+1, y1+1)] - sumSq[idx(x0, y1+1)] - sumSq[idx(x1+1, y0)] + sumSq[idx(x0, y0)]
+
+			mean := s / area
+			variance := sq/area - mean*mean
+			if variance < 0 {
+				variance = 0
+			}
+			stddev := math.Sqrt(variance)
+
+			threshold := mean * (1 + k*(stddev/R-1))
+
+			v := float64(gray.GrayAt(b.Min.X+x, b.Min.Y+y).Y)
+			if v >= threshold {
+				out.SetGray(b.Min.X+x, b.Min.Y+y, color.Gray{Y: 255})
+			} else {
+				out.SetGray(b.Min.X+x, b.Min.Y+y, color.Gray{Y: 0})
+			}
+		}
+	}
+
+	return out
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+var validImageExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+}
+
+var archiveExts = map[string]bool{
+	".zip": true,
+	".cbz": true,
+	".cbr": true,
+}
+
+// foundImage is a scanned source image together with the path relative to
+// the root of the pattern/directory/archive it was found under, so outputs
+// can mirror the input tree beneath compressed/. SourcePath+Open follow the
+// same convention as ImageJob: exactly one of them is set.
+type foundImage struct {
+	SourcePath  string
+	DisplayName string
+	Open        func() (io.ReadCloser, error)
+	RelPath     string
+	OrigSize    int64
+	ModTime     int64
+}
+
+// scanImages resolves a list of directories, glob patterns (which may
+// contain a recursive "**" segment, filepathx-style) and archive paths
+// (.zip/.cbz/.cbr) into the set of image files to process, skipping
+// anything matched by excludePatterns.
+func scanImages(patterns []string, excludePatterns []string) ([]foundImage, error) {
+	var found []foundImage
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		info, err := os.Stat(pattern)
+
+		if err == nil && !info.IsDir() && archiveExts[strings.ToLower(filepath.Ext(pattern))] {
+			if err := scanArchive(pattern, "", excludePatterns, &found, seen); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err == nil && info.IsDir() {
+			nsPrefix := filepath.Base(filepath.Clean(pattern))
+			entries, err := os.ReadDir(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				path := filepath.Join(pattern, entry.Name())
+				ext := strings.ToLower(filepath.Ext(entry.Name()))
+
+				if archiveExts[ext] {
+					if err := scanArchive(path, nsPrefix, excludePatterns, &found, seen); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if !validImageExts[ext] {
+					continue
+				}
+				fi, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				relPath := filepath.Join(nsPrefix, entry.Name())
+				addFoundImage(&found, seen, path, relPath, fi.Size(), fi.ModTime().Unix(), excludePatterns)
+			}
+			continue
+		}
+
+		matches, err := globPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		base := globBase(pattern)
+		nsPrefix := filepath.Base(base)
+		for _, path := range matches {
+			fi, err := os.Stat(path)
+			if err != nil || fi.IsDir() {
+				continue
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+
+			if archiveExts[ext] {
+				if err := scanArchive(path, nsPrefix, excludePatterns, &found, seen); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !validImageExts[ext] {
+				continue
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				rel = filepath.Base(path)
+			}
+			relPath := filepath.Join(nsPrefix, rel)
+			addFoundImage(&found, seen, path, relPath, fi.Size(), fi.ModTime().Unix(), excludePatterns)
+		}
+	}
+
+	return found, nil
+}
+
+func addFoundImage(found *[]foundImage, seen map[string]bool, path, relPath string, size, modTime int64, excludePatterns []string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if seen[abs] {
+		return
+	}
+	if isExcluded(relPath, excludePatterns) {
+		return
+	}
+	seen[abs] = true
+	*found = append(*found, foundImage{SourcePath: path, RelPath: relPath, OrigSize: size, ModTime: modTime})
+}
+
+// scanArchive opens a .zip/.cbz/.cbr input and queues each image entry it
+// contains as a foundImage rooted under the archive's base name (without
+// extension), so multiple archives processed together don't collide.
+// nsPrefix further namespaces that root when the archive itself was found
+// via a directory or glob pattern scanned alongside others (see
+// scanImages), so e.g. "d1/comic.cbz" and "d2/comic.cbz" don't collide the
+// way two bare "comic.cbz" roots would.
+func scanArchive(path, nsPrefix string, excludePatterns []string, found *[]foundImage, seen map[string]bool) error {
+	base := filepath.Join(nsPrefix, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if ext == ".cbr" {
+		dir, err := extractCBR(path, cbrTool)
+		if err != nil {
+			return err
+		}
+		extractedDirs = append(extractedDirs, dir)
+
+		return filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			if !validImageExts[strings.ToLower(filepath.Ext(p))] {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				rel = filepath.Base(p)
+			}
+			relPath := filepath.Join(base, rel)
+			if isExcluded(relPath, excludePatterns) {
+				return nil
+			}
+			addFoundImage(found, seen, p, relPath, fi.Size(), fi.ModTime().Unix(), excludePatterns)
+			return nil
+		})
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir %s: %v", path, err)
+	}
+	openArchives = append(openArchives, zr)
+
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		if !validImageExts[strings.ToLower(filepath.Ext(entry.Name))] {
+			continue
+		}
+
+		relPath := filepath.Join(base, filepath.FromSlash(entry.Name))
+		if isExcluded(relPath, excludePatterns) {
+			continue
+		}
+
+		key := path + "::" + entry.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		entry := entry
+		*found = append(*found, foundImage{
+			DisplayName: entry.Name,
+			Open:        func() (io.ReadCloser, error) { return entry.Open() },
+			RelPath:     relPath,
+			OrigSize:    int64(entry.UncompressedSize64),
+			ModTime:     entry.Modified.Unix(),
+		})
+	}
+
+	return nil
+}
+
+// findArchiveTool probes PATH for an external tool able to extract .cbr
+// (RAR) archives, failing early rather than partway through a batch.
+func findArchiveTool() (string, error) {
+	for _, tool := range []string{"unrar", "7z", "7za"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("nenhuma ferramenta para extrair CBR encontrada no PATH (unrar, 7z ou 7za)")
+}
+
+// extractCBR extracts a .cbr archive into a temporary directory using
+// whichever tool findArchiveTool resolved, returning that directory so it
+// can be scanned like a regular folder.
+func extractCBR(path, tool string) (string, error) {
+	dir, err := os.MkdirTemp("", "pink-cbr-*")
+	if err != nil {
+		return "", err
+	}
+
+	var cmd *exec.Cmd
+	if tool == "unrar" {
+		cmd = exec.Command("unrar", "x", "-y", path, dir+string(os.PathSeparator))
+	} else {
+		cmd = exec.Command(tool, "x", "-y", "-o"+dir, path)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("%s error: %v - %s", tool, err, stderr.String())
+	}
+
+	return dir, nil
+}
+
+// closeArchiveResources releases archive readers and temporary extraction
+// directories opened while scanning .zip/.cbz/.cbr inputs.
+func closeArchiveResources() {
+	for _, c := range openArchives {
+		c.Close()
+	}
+	for _, dir := range extractedDirs {
+		os.RemoveAll(dir)
+	}
+}
+
+func isExcluded(path string, excludePatterns []string) bool {
+	for _, pattern := range excludePatterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globBase returns the non-wildcard root directory of a glob pattern, used
+// to compute relative sub-paths for mirroring the input tree on output.
+func globBase(pattern string) string {
+	clean := filepath.ToSlash(pattern)
+	parts := strings.Split(clean, "/")
+	var root []string
+	for _, part := range parts {
+		if hasMeta(part) {
+			break
+		}
+		root = append(root, part)
+	}
+	if len(root) == 0 {
+		return "."
+	}
+	return filepath.Clean(strings.Join(root, "/"))
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[{")
+}
+
+// globPattern expands a glob pattern into matching file paths. It supports
+// brace alternatives like "*.{png,jpg}" and a recursive "**" segment that
+// walks every subdirectory, inspired by the filepathx approach of splitting
+// on "**" and re-globbing each directory found by filepath.Walk.
+func globPattern(pattern string) ([]string, error) {
+	var all []string
+	seen := make(map[string]bool)
+
+	for _, p := range expandBraces(pattern) {
+		matches, err := globStar(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				all = append(all, m)
+			}
+		}
+	}
+
+	return all, nil
+}
+
+func expandBraces(pattern string) []string {
+	open := strings.Index(pattern, "{")
+	if open == -1 {
+		return []string{pattern}
+	}
+	closeIdx := strings.Index(pattern[open:], "}")
+	if closeIdx == -1 {
+		return []string{pattern}
+	}
+	closeIdx += open
+
+	prefix, suffix := pattern[:open], pattern[closeIdx+1:]
+	var out []string
+	for _, alt := range strings.Split(pattern[open+1:closeIdx], ",") {
+		out = append(out, expandBraces(prefix+alt+suffix)...)
+	}
+	return out
+}
+
+func globStar(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	parts := strings.SplitN(pattern, "**", 2)
+	prefix, suffix := strings.TrimSuffix(parts[0], "/"), parts[1]
+	if prefix == "" {
+		prefix = "."
+	}
+	prefix = filepath.Clean(prefix)
+
+	var matches []string
+	err := filepath.Walk(prefix, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			sub, err := globStar(p + suffix)
+			if err != nil {
+				return err
+			}
+			matches = append(matches, sub...)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+func showPreview(found []foundImage, outputPath string) {
+	fmt.Println(strings.Repeat("─", 80))
+	logInfo("PREVIEW - Arquivos que serão convertidos:")
+	fmt.Println()
+
+	var totalSize int64
+
+	for _, f := range found {
+		name := f.DisplayName
+		if f.SourcePath != "" {
+			name = filepath.Base(f.SourcePath)
+		}
+
+		destFilename := webpDestName(f.RelPath)
+
+		totalSize += f.OrigSize
+
+		fmt.Printf("  %s (%s) → %s\n",
+			name,
+			formatSize(f.OrigSize),
+			destFilename,
+		)
+	}
+
+	fmt.Println()
+	if outputArchivePath != "" {
+		logInfo(fmt.Sprintf("Arquivo de destino: %s", outputArchivePath))
+	} else {
+		logInfo(fmt.Sprintf("Pasta de destino: %s", outputPath))
+	}
+	logInfo(fmt.Sprintf("Tamanho total: %s", formatSize(totalSize)))
+	fmt.Println(strings.Repeat("─", 80))
+}
+
+func confirmExecution() bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("\nDeseja continuar? (S/n): ")
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "" || response == "s" || response == "sim"
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func logInfo(msg string) {
+	fmt.Printf("\033[36m[INFO]\033[0m %s\n", msg)
+}
+
+func logSuccess(msg string) {
+	fmt.Printf("\033[32m[✓]\033[0m %s\n", msg)
+}
+
+func logError(msg string) {
+	fmt.Printf("\033[31m[✗]\033[0m %s\n", msg)
+}
+
+func printSummary(stats Stats) {
+	fmt.Println(strings.Repeat("─", 80))
+	logInfo(fmt.Sprintf("Total de arquivos: %d", stats.TotalFiles))
+	logSuccess(fmt.Sprintf("Processados: %d", stats.ProcessedFiles))
+
+	if stats.FailedFiles > 0 {
+		logError(fmt.Sprintf("Falhas: %d", stats.FailedFiles))
+	}
+
+	if stats.ProcessedFiles > 0 {
+		reduction := float64(stats.OriginalSize-stats.ConvertedSize) / float64(stats.OriginalSize) * 100
+		fmt.Printf("\033[36m[STATS]\033[0m Tamanho original: %s → Convertido: %s (%.1f%% redução)\n",
+			formatSize(stats.OriginalSize),
+			formatSize(stats.ConvertedSize),
+			reduction,
+		)
+	}
+
+	fmt.Println(strings.Repeat("─", 80))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    []string
+	}{
+		{"img.{png,jpg}", []string{"img.png", "img.jpg"}},
+		{"a/{b,c}/{d,e}.png", []string{"a/b/d.png", "a/b/e.png", "a/c/d.png", "a/c/e.png"}},
+		{"img.png", []string{"img.png"}},
+		{"img.{png", []string{"img.{png"}},
+	}
+
+	for _, c := range cases {
+		got := expandBraces(c.pattern)
+		if !equalStrings(got, c.want) {
+			t.Errorf("expandBraces(%q) = %v, want %v", c.pattern, got, c.want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGlobStarAtStart(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.png"))
+	writeTestFile(t, filepath.Join(dir, "sub", "b.png"))
+	writeTestFile(t, filepath.Join(dir, "sub", "deep", "c.png"))
+
+	matches, err := globPattern(filepath.Join(dir, "**", "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+
+	want := []string{
+		filepath.Join(dir, "a.png"),
+		filepath.Join(dir, "sub", "b.png"),
+		filepath.Join(dir, "sub", "deep", "c.png"),
+	}
+	sort.Strings(want)
+
+	if !equalStrings(matches, want) {
+		t.Errorf("globPattern(**) = %v, want %v", matches, want)
+	}
+}
+
+func TestGlobStarAtEnd(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.png"))
+	writeTestFile(t, filepath.Join(dir, "sub", "b.png"))
+
+	matches, err := globPattern(filepath.Join(dir, "**"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err != nil || !fi.IsDir() {
+			t.Errorf("globPattern(dir/**) returned non-directory match %q", m)
+		}
+	}
+	if len(matches) == 0 {
+		t.Error("globPattern(dir/**) returned no matches")
+	}
+}
+
+func TestGlobPatternDedup(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.png"))
+
+	matches, err := globPattern(filepath.Join(dir, "{a,a}.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("globPattern with overlapping brace alternatives = %v, want 1 deduped match", matches)
+	}
+}
+
+func TestGlobPatternUnmatchedBrace(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "{a.png"))
+
+	matches, err := globPattern(filepath.Join(dir, "{a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0] != filepath.Join(dir, "{a.png") {
+		t.Errorf("globPattern with unmatched brace = %v, want literal match", matches)
+	}
+}
+
+func TestScanImagesNamespacesSameNameAcrossDirs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "d1", "img.png"))
+	writeTestFile(t, filepath.Join(root, "d2", "img.png"))
+
+	found, err := scanImages([]string{filepath.Join(root, "d1"), filepath.Join(root, "d2")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("scanImages across two same-named files in different dirs = %d entries, want 2", len(found))
+	}
+
+	relPaths := map[string]bool{}
+	for _, f := range found {
+		relPaths[f.RelPath] = true
+	}
+	if len(relPaths) != 2 {
+		t.Errorf("scanImages RelPaths = %v, want two distinct (namespaced) entries", relPaths)
+	}
+}
+
+func TestScanImagesNamespacesSameNameAcrossGlobs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "d1", "img.png"))
+	writeTestFile(t, filepath.Join(root, "d2", "img.png"))
+
+	found, err := scanImages([]string{
+		filepath.Join(root, "d1", "*.png"),
+		filepath.Join(root, "d2", "*.png"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("scanImages across two same-named globbed files = %d entries, want 2", len(found))
+	}
+
+	relPaths := map[string]bool{}
+	for _, f := range found {
+		relPaths[f.RelPath] = true
+	}
+	if len(relPaths) != 2 {
+		t.Errorf("scanImages RelPaths = %v, want two distinct (namespaced) entries", relPaths)
+	}
+}
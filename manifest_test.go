@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func withVerifyForce(t *testing.T, v, f bool) {
+	t.Helper()
+	prevVerify, prevForce := verify, force
+	verify, force = v, f
+	t.Cleanup(func() { verify, force = prevVerify, prevForce })
+}
+
+func newTestFoundImage(t *testing.T, dir, relPath, content string) foundImage {
+	t.Helper()
+	p := filepath.Join(dir, relPath)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return foundImage{SourcePath: p, RelPath: relPath, OrigSize: fi.Size(), ModTime: fi.ModTime().Unix()}
+}
+
+func TestFilterResumableSkipsMatchingEntry(t *testing.T) {
+	withVerifyForce(t, false, false)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	manifest := &Manifest{Entries: map[string]ManifestEntry{
+		"a.png": {Size: f.OrigSize, ModTime: f.ModTime},
+	}}
+
+	kept := filterResumable([]foundImage{f}, manifest, dir)
+	if len(kept) != 0 {
+		t.Errorf("filterResumable kept %d entries, want 0 for an already-processed match", len(kept))
+	}
+}
+
+func TestFilterResumableKeepsMismatchedEntry(t *testing.T) {
+	withVerifyForce(t, false, false)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	manifest := &Manifest{Entries: map[string]ManifestEntry{
+		"a.png": {Size: f.OrigSize + 1, ModTime: f.ModTime},
+	}}
+
+	kept := filterResumable([]foundImage{f}, manifest, dir)
+	if len(kept) != 1 {
+		t.Errorf("filterResumable kept %d entries, want 1 for a size mismatch", len(kept))
+	}
+}
+
+func TestFilterResumableForceBypassesManifest(t *testing.T) {
+	withVerifyForce(t, false, true)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	manifest := &Manifest{Entries: map[string]ManifestEntry{
+		"a.png": {Size: f.OrigSize, ModTime: f.ModTime},
+	}}
+
+	kept := filterResumable([]foundImage{f}, manifest, dir)
+	if len(kept) != 1 {
+		t.Errorf("filterResumable with --force kept %d entries, want 1 (everything reprocessed)", len(kept))
+	}
+}
+
+func TestVerifyEntryDetectsCorruptedOutput(t *testing.T) {
+	withVerifyForce(t, true, false)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	srcHash, err := hashFoundImage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destPath := filepath.Join(dir, webpDestName(f.RelPath))
+	if err := os.WriteFile(destPath, []byte("good-output"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outHash, err := hashFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := ManifestEntry{Size: f.OrigSize, ModTime: f.ModTime, Hash: srcHash, OutHash: outHash}
+	if !verifyEntry(f, entry, dir) {
+		t.Fatal("verifyEntry rejected an untouched, matching entry")
+	}
+
+	if err := os.WriteFile(destPath, []byte("corrupted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if verifyEntry(f, entry, dir) {
+		t.Error("verifyEntry accepted an entry whose destination bytes no longer match outHash")
+	}
+}
+
+func TestVerifyEntryDetectsMissingOutput(t *testing.T) {
+	withVerifyForce(t, true, false)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	srcHash, err := hashFoundImage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := ManifestEntry{Size: f.OrigSize, ModTime: f.ModTime, Hash: srcHash, OutHash: "deadbeef"}
+	if verifyEntry(f, entry, dir) {
+		t.Error("verifyEntry accepted an entry whose destination file doesn't exist")
+	}
+}
+
+func TestVerifyEntryDetectsChangedSource(t *testing.T) {
+	withVerifyForce(t, true, false)
+
+	dir := t.TempDir()
+	f := newTestFoundImage(t, dir, "a.png", "source-bytes")
+
+	destPath := filepath.Join(dir, webpDestName(f.RelPath))
+	if err := os.WriteFile(destPath, []byte("good-output"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outHash, err := hashFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := ManifestEntry{Size: f.OrigSize, ModTime: f.ModTime, Hash: "stale-hash-from-before-the-edit", OutHash: outHash}
+	if verifyEntry(f, entry, dir) {
+		t.Error("verifyEntry accepted an entry whose source content changed")
+	}
+}
+
+func TestManifestSaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{Entries: map[string]ManifestEntry{
+		"a.png": {Size: 10, ModTime: 20, Hash: "h1", OutHash: "o1"},
+	}}
+
+	if err := m.save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := loadManifest(dir)
+	if got := loaded.Entries["a.png"]; got != m.Entries["a.png"] {
+		t.Errorf("loadManifest round-trip = %+v, want %+v", got, m.Entries["a.png"])
+	}
+}